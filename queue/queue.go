@@ -2,7 +2,6 @@ package queue
 
 import (
 	"context"
-	"encoding/base64"
 	"fmt"
 	"strconv"
 	"strings"
@@ -30,7 +29,12 @@ type HandlerFunc func(ctx context.Context, event eventsource.Event) error
 type logFunc func(format string, args ...interface{})
 
 type Options struct {
-	printf logFunc
+	printf       logFunc
+	retryPolicy  RetryPolicy
+	deadLetter   DeadLetterFunc
+	metrics      Metrics
+	concurrency  int
+	partitionKey func(eventsource.Event) string
 }
 
 type Option func(*Options)
@@ -41,9 +45,57 @@ func WithLogger(fn func(format string, args ...interface{})) func(*Options) {
 	}
 }
 
+// WithRetryPolicy overrides the default RetryPolicy (3 attempts, 1s base
+// delay, 30s max delay) applied to each message.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(o *Options) {
+		o.retryPolicy = policy
+	}
+}
+
+// WithDeadLetter registers fn to be called when a message's retries are
+// exhausted or its payload cannot be decoded. If unset, the message is
+// logged and acked without further action.
+func WithDeadLetter(fn DeadLetterFunc) Option {
+	return func(o *Options) {
+		o.deadLetter = fn
+	}
+}
+
+// WithMetrics wires m into receive, handle-success, handle-fail, and
+// dead-letter counters.
+func WithMetrics(m Metrics) Option {
+	return func(o *Options) {
+		o.metrics = m
+	}
+}
+
+// WithConcurrency fans handling out to n worker goroutines instead of
+// processing messages one at a time. Combine with WithPartitionKey to
+// preserve per-aggregate ordering across the worker pool.
+func WithConcurrency(n int) Option {
+	return func(o *Options) {
+		o.concurrency = n
+	}
+}
+
+// WithPartitionKey routes events to a worker by hashing fn(event) mod the
+// configured concurrency, so events sharing a key (e.g. an aggregate ID)
+// are always handled by the same worker and never run out of order
+// relative to each other, while events with different keys may run in
+// parallel. Has no effect without WithConcurrency(n) where n > 1.
+func WithPartitionKey(fn func(eventsource.Event) string) Option {
+	return func(o *Options) {
+		o.partitionKey = fn
+	}
+}
+
 func Subscribe(api sqsiface.SQSAPI, queueName string, serializer eventsource.Serializer, fn HandlerFunc, opts ...Option) (*Subscription, error) {
 	var options = Options{
-		printf: func(format string, args ...interface{}) {},
+		printf:      func(format string, args ...interface{}) {},
+		retryPolicy: defaultRetryPolicy,
+		metrics:     noopMetrics{},
+		concurrency: 1,
 	}
 	for _, opt := range opts {
 		opt(&options)
@@ -85,7 +137,7 @@ func Subscribe(api sqsiface.SQSAPI, queueName string, serializer eventsource.Ser
 		return receiveLoop(ctx, options.printf, api, queueUrl, received)
 	})
 	group.Go(func() error {
-		return handleLoop(ctx, options.printf, received, completed, serializer, fn)
+		return runHandlers(ctx, options, api, queueUrl, received, completed, serializer, fn)
 	})
 	group.Go(func() error {
 		return deleteLoop(ctx, options.printf, api, queueUrl, 15*time.Second, completed)
@@ -131,33 +183,14 @@ func receiveLoop(ctx context.Context, printf logFunc, api sqsiface.SQSAPI, queue
 	}
 }
 
-func handleMessage(ctx context.Context, printf logFunc, serializer eventsource.Serializer, fn HandlerFunc, m *sqs.Message) error {
-	if m == nil || m.Body == nil {
-		return nil
-	}
-
-	data, err := base64.StdEncoding.DecodeString(*m.Body)
-	if err != nil {
-		printf("unable to decode sqs body - %v\n", err)
-		return nil
-	}
-
-	event, err := serializer.UnmarshalEvent(eventsource.Record{Data: data})
-	if err != nil {
-		return fmt.Errorf("unable to unmarshal event -> %v", *m.Body)
-	}
-
-	return fn(ctx, event)
-}
-
-func handleLoop(ctx context.Context, printf logFunc, received, completed chan *sqs.Message, serializer eventsource.Serializer, fn HandlerFunc) error {
+func handleLoop(ctx context.Context, printf logFunc, api sqsiface.SQSAPI, queueUrl *string, received, completed chan *sqs.Message, serializer eventsource.Serializer, fn HandlerFunc, policy RetryPolicy, deadLetter DeadLetterFunc, metrics Metrics) error {
 	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 
 		case v := <-received:
-			if err := handleMessage(ctx, printf, serializer, fn, v); err != nil {
+			if err := processMessage(ctx, printf, api, queueUrl, serializer, fn, v, policy, deadLetter, metrics); err != nil {
 				printf("unable to handle event - %v", err)
 				return err
 			}
@@ -0,0 +1,148 @@
+package queue
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/eventsource-ecosystem/eventsource"
+)
+
+// RetryPolicy controls how many times a message's HandlerFunc is retried
+// before it is handed off to DeadLetter, and how long to back off between
+// attempts.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+var defaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   time.Second,
+	MaxDelay:    30 * time.Second,
+}
+
+// backoff returns an exponential delay with jitter for the given attempt
+// number (1-indexed).
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay << uint(attempt-1)
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// DeadLetterFunc is invoked when a message's retries are exhausted, or its
+// payload cannot be decoded at all. The message is acked (deleted from the
+// queue) regardless of the error DeadLetterFunc returns, so SQS's own
+// redrive policy never sees it again; implementations should forward the
+// message to their own DLQ or archive if they want to keep it.
+type DeadLetterFunc func(ctx context.Context, m *sqs.Message, err error) error
+
+// Metrics receives counters for message handling outcomes. Implement this to
+// wire handleLoop into a metrics backend such as StatsD or Prometheus.
+type Metrics interface {
+	IncReceived()
+	IncHandleSuccess()
+	IncHandleFailure()
+	IncDeadLetter()
+}
+
+type noopMetrics struct{}
+
+func (noopMetrics) IncReceived()      {}
+func (noopMetrics) IncHandleSuccess() {}
+func (noopMetrics) IncHandleFailure() {}
+func (noopMetrics) IncDeadLetter()    {}
+
+func decodeMessage(serializer eventsource.Serializer, m *sqs.Message) (eventsource.Event, error) {
+	data, err := base64.StdEncoding.DecodeString(*m.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode sqs body - %v", err)
+	}
+
+	event, err := serializer.UnmarshalEvent(eventsource.Record{Data: data})
+	if err != nil {
+		return nil, fmt.Errorf("unable to unmarshal event -> %v", *m.Body)
+	}
+
+	return event, nil
+}
+
+// processMessage decodes m and invokes fn, retrying per policy on handler
+// error and changing the message's visibility timeout between attempts so it
+// doesn't become visible to other consumers mid-retry. A decode failure is
+// not retryable and is sent straight to deadLetter.
+func processMessage(ctx context.Context, printf logFunc, api sqsiface.SQSAPI, queueUrl *string, serializer eventsource.Serializer, fn HandlerFunc, m *sqs.Message, policy RetryPolicy, deadLetter DeadLetterFunc, metrics Metrics) error {
+	if m == nil || m.Body == nil {
+		return nil
+	}
+
+	metrics.IncReceived()
+
+	event, err := decodeMessage(serializer, m)
+	if err != nil {
+		printf("unable to decode sqs message - %v", err)
+		metrics.IncHandleFailure()
+		return sendToDeadLetter(ctx, printf, deadLetter, metrics, m, err)
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx, event)
+		if err == nil {
+			metrics.IncHandleSuccess()
+			return nil
+		}
+
+		metrics.IncHandleFailure()
+		printf("unable to handle event, attempt %v/%v - %v", attempt, policy.MaxAttempts, err)
+
+		if attempt >= policy.MaxAttempts {
+			return sendToDeadLetter(ctx, printf, deadLetter, metrics, m, err)
+		}
+
+		delay := policy.backoff(attempt)
+		// Hide the message for the backoff delay plus the same 240s handling
+		// window receiveLoop requests, so it stays hidden through the next attempt.
+		if _, err := api.ChangeMessageVisibilityWithContext(ctx, &sqs.ChangeMessageVisibilityInput{
+			QueueUrl:          queueUrl,
+			ReceiptHandle:     m.ReceiptHandle,
+			VisibilityTimeout: aws.Int64(int64((delay + 240*time.Second).Seconds())),
+		}); err != nil {
+			printf("unable to change message visibility - %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// sendToDeadLetter always returns nil so that the caller still acks m even
+// if deadLetter itself fails - a failing DeadLetterFunc (e.g. its own DLQ
+// write timing out) must not be treated like a handler error, or it would
+// tear down the whole subscription and leave a poison message to redrive
+// forever.
+func sendToDeadLetter(ctx context.Context, printf logFunc, deadLetter DeadLetterFunc, metrics Metrics, m *sqs.Message, cause error) error {
+	metrics.IncDeadLetter()
+
+	if deadLetter == nil {
+		printf("dead-lettering message - %v", cause)
+		return nil
+	}
+
+	if err := deadLetter(ctx, m, cause); err != nil {
+		printf("dead letter callback failed - %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package queue
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/eventsource-ecosystem/eventsource"
+)
+
+type RetryMock struct {
+	sqsiface.SQSAPI
+
+	visibilityChanges int
+}
+
+func (m *RetryMock) ChangeMessageVisibilityWithContext(ctx aws.Context, input *sqs.ChangeMessageVisibilityInput, opts ...request.Option) (*sqs.ChangeMessageVisibilityOutput, error) {
+	m.visibilityChanges++
+	return &sqs.ChangeMessageVisibilityOutput{}, nil
+}
+
+func TestProcessMessageDeadLetterAfterRetries(t *testing.T) {
+	var (
+		ctx        = context.Background()
+		serializer = eventsource.NewJSONSerializer(Sample{})
+		event      = Sample{
+			Model: eventsource.Model{ID: "abc"},
+			Name:  "blah",
+		}
+		api = &RetryMock{}
+	)
+
+	record, err := serializer.MarshalEvent(event)
+	if err != nil {
+		t.Fatalf("got %v; want nil", err)
+	}
+
+	m := &sqs.Message{
+		Body: aws.String(base64.StdEncoding.EncodeToString(record.Data)),
+	}
+
+	var (
+		attempts     int
+		deadLettered bool
+	)
+	fn := func(ctx context.Context, event eventsource.Event) error {
+		attempts++
+		return errors.New("boom")
+	}
+	deadLetter := func(ctx context.Context, m *sqs.Message, err error) error {
+		deadLettered = true
+		return nil
+	}
+
+	policy := RetryPolicy{MaxAttempts: 2, BaseDelay: 0, MaxDelay: 0}
+
+	err = processMessage(ctx, func(string, ...interface{}) {}, api, aws.String("url"), serializer, fn, m, policy, deadLetter, noopMetrics{})
+	if err != nil {
+		t.Fatalf("got %v; want nil", err)
+	}
+
+	if got, want := attempts, 2; got != want {
+		t.Fatalf("attempts: got %v; want %v", got, want)
+	}
+	if !deadLettered {
+		t.Fatal("expected message to be dead-lettered")
+	}
+	if got, want := api.visibilityChanges, 1; got != want {
+		t.Fatalf("visibilityChanges: got %v; want %v", got, want)
+	}
+}
+
+func TestProcessMessageAcksDespiteDeadLetterError(t *testing.T) {
+	var (
+		ctx        = context.Background()
+		serializer = eventsource.NewJSONSerializer(Sample{})
+		event      = Sample{
+			Model: eventsource.Model{ID: "abc"},
+			Name:  "blah",
+		}
+		api = &RetryMock{}
+	)
+
+	record, err := serializer.MarshalEvent(event)
+	if err != nil {
+		t.Fatalf("got %v; want nil", err)
+	}
+
+	m := &sqs.Message{
+		Body: aws.String(base64.StdEncoding.EncodeToString(record.Data)),
+	}
+
+	fn := func(ctx context.Context, event eventsource.Event) error {
+		return errors.New("boom")
+	}
+	deadLetter := func(ctx context.Context, m *sqs.Message, err error) error {
+		return errors.New("dlq archive write failed")
+	}
+
+	policy := RetryPolicy{MaxAttempts: 1, BaseDelay: 0, MaxDelay: 0}
+
+	err = processMessage(ctx, func(string, ...interface{}) {}, api, aws.String("url"), serializer, fn, m, policy, deadLetter, noopMetrics{})
+	if err != nil {
+		t.Fatalf("got %v; want nil - a failing DeadLetterFunc must not tear down the subscription or block the ack", err)
+	}
+}
+
+func TestProcessMessageUndecodableDeadLetters(t *testing.T) {
+	var (
+		ctx        = context.Background()
+		serializer = eventsource.NewJSONSerializer(Sample{})
+		api        = &RetryMock{}
+		m          = &sqs.Message{Body: aws.String("not-base64!!")}
+	)
+
+	var deadLettered bool
+	deadLetter := func(ctx context.Context, m *sqs.Message, err error) error {
+		deadLettered = true
+		return nil
+	}
+	fn := func(ctx context.Context, event eventsource.Event) error {
+		t.Fatal("fn should not be called for an undecodable message")
+		return nil
+	}
+
+	err := processMessage(ctx, func(string, ...interface{}) {}, api, aws.String("url"), serializer, fn, m, defaultRetryPolicy, deadLetter, noopMetrics{})
+	if err != nil {
+		t.Fatalf("got %v; want nil", err)
+	}
+	if !deadLettered {
+		t.Fatal("expected message to be dead-lettered")
+	}
+}
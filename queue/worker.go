@@ -0,0 +1,84 @@
+package queue
+
+import (
+	"context"
+	"hash/fnv"
+
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/eventsource-ecosystem/eventsource"
+	"golang.org/x/sync/errgroup"
+)
+
+// runHandlers drives message handling for a subscription according to
+// options.concurrency and options.partitionKey. With the default
+// concurrency of 1, it behaves exactly like a single handleLoop. With a
+// higher concurrency and no partition key, N workers all read from the
+// shared received channel with no ordering guarantee. With a partition key,
+// a dispatcher hashes each event's key to a fixed worker so that events
+// sharing a key are always handled by the same worker, preserving per-key
+// ordering while allowing parallelism across keys.
+func runHandlers(ctx context.Context, options Options, api sqsiface.SQSAPI, queueUrl *string, received, completed chan *sqs.Message, serializer eventsource.Serializer, fn HandlerFunc) error {
+	n := options.concurrency
+	if n < 1 {
+		n = 1
+	}
+
+	if n == 1 {
+		return handleLoop(ctx, options.printf, api, queueUrl, received, completed, serializer, fn, options.retryPolicy, options.deadLetter, options.metrics)
+	}
+
+	group, ctx := errgroup.WithContext(ctx)
+
+	if options.partitionKey == nil {
+		for i := 0; i < n; i++ {
+			group.Go(func() error {
+				return handleLoop(ctx, options.printf, api, queueUrl, received, completed, serializer, fn, options.retryPolicy, options.deadLetter, options.metrics)
+			})
+		}
+		return group.Wait()
+	}
+
+	workers := make([]chan *sqs.Message, n)
+	for i := range workers {
+		workers[i] = make(chan *sqs.Message, 10)
+
+		worker := workers[i]
+		group.Go(func() error {
+			return handleLoop(ctx, options.printf, api, queueUrl, worker, completed, serializer, fn, options.retryPolicy, options.deadLetter, options.metrics)
+		})
+	}
+
+	group.Go(func() error {
+		return partitionLoop(ctx, serializer, options.partitionKey, received, workers)
+	})
+
+	return group.Wait()
+}
+
+func partitionLoop(ctx context.Context, serializer eventsource.Serializer, partitionKey func(eventsource.Event) string, received chan *sqs.Message, workers []chan *sqs.Message) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case m := <-received:
+			idx := 0
+			if event, err := decodeMessage(serializer, m); err == nil {
+				idx = partitionIndex(partitionKey(event), len(workers))
+			}
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case workers[idx] <- m:
+			}
+		}
+	}
+}
+
+func partitionIndex(key string, n int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(n))
+}
@@ -0,0 +1,61 @@
+package queue
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/eventsource-ecosystem/eventsource"
+)
+
+func TestPartitionIndexStable(t *testing.T) {
+	if got, want := partitionIndex("abc", 4), partitionIndex("abc", 4); got != want {
+		t.Fatalf("expected partitionIndex to be deterministic, got %v and %v", got, want)
+	}
+}
+
+func TestPartitionLoopRoutesSameKeyToSameWorker(t *testing.T) {
+	var (
+		ctx, cancel = context.WithCancel(context.Background())
+		serializer  = eventsource.NewJSONSerializer(Sample{})
+		received    = make(chan *sqs.Message, 10)
+		workers     = []chan *sqs.Message{
+			make(chan *sqs.Message, 10),
+			make(chan *sqs.Message, 10),
+		}
+		partitionKey = func(event eventsource.Event) string {
+			return event.AggregateID()
+		}
+	)
+	defer cancel()
+
+	toMessage := func(id string) *sqs.Message {
+		record, err := serializer.MarshalEvent(Sample{Model: eventsource.Model{ID: id}, Name: "blah"})
+		if err != nil {
+			t.Fatalf("got %v; want nil", err)
+		}
+		return &sqs.Message{Body: aws.String(base64.StdEncoding.EncodeToString(record.Data))}
+	}
+
+	go partitionLoop(ctx, serializer, partitionKey, received, workers)
+
+	received <- toMessage("abc")
+	received <- toMessage("abc")
+
+	idx := partitionIndex("abc", len(workers))
+	other := 1 - idx
+
+	select {
+	case <-workers[idx]:
+	case <-workers[other]:
+		t.Fatal("message routed to the wrong worker for its partition key")
+	}
+
+	select {
+	case <-workers[idx]:
+	case <-workers[other]:
+		t.Fatal("second message for the same key routed to a different worker")
+	}
+}
@@ -0,0 +1,95 @@
+package s3firehose
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// Checkpoint lets a long-running Replay resume after a crash rather than
+// starting from scratch. Objects are assumed to be processed in
+// lexicographic key order; Save is called with the key of each object that
+// completes without a handler error.
+type Checkpoint interface {
+	// Load returns the last completed key, or an empty string if replay
+	// has not made any progress yet.
+	Load(ctx context.Context) (key string, err error)
+
+	// Save records key as the last object that completed successfully.
+	Save(ctx context.Context, key string) error
+}
+
+// MemoryCheckpoint is an in-memory Checkpoint. It is useful for testing, or
+// for a Replay that only needs to resume within a single process's
+// lifetime; state does not survive a restart.
+type MemoryCheckpoint struct {
+	mu  sync.Mutex
+	key string
+}
+
+func (c *MemoryCheckpoint) Load(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.key, nil
+}
+
+func (c *MemoryCheckpoint) Save(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.key = key
+	return nil
+}
+
+// S3Checkpoint persists the checkpoint as the body of a single S3 object, so
+// it survives process restarts and can be shared across replay runs. Key
+// should point at a location dedicated to operational state, separate from
+// the Firehose-delivered objects being replayed, e.g.
+// "checkpoints/master-events--integration".
+type S3Checkpoint struct {
+	API    s3iface.S3API
+	Bucket string
+	Key    string
+}
+
+func (c *S3Checkpoint) Load(ctx context.Context) (string, error) {
+	output, err := c.API.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(c.Bucket),
+		Key:    aws.String(c.Key),
+	})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == s3.ErrCodeNoSuchKey {
+			return "", nil
+		}
+		return "", fmt.Errorf("unable to load checkpoint s3://%v/%v - %v", c.Bucket, c.Key, err)
+	}
+	defer output.Body.Close()
+
+	data, err := ioutil.ReadAll(output.Body)
+	if err != nil {
+		return "", fmt.Errorf("unable to read checkpoint s3://%v/%v - %v", c.Bucket, c.Key, err)
+	}
+
+	return string(data), nil
+}
+
+func (c *S3Checkpoint) Save(ctx context.Context, key string) error {
+	_, err := c.API.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(c.Bucket),
+		Key:    aws.String(c.Key),
+		Body:   bytes.NewReader([]byte(key)),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to save checkpoint s3://%v/%v - %v", c.Bucket, c.Key, err)
+	}
+
+	return nil
+}
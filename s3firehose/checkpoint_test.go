@@ -0,0 +1,175 @@
+package s3firehose
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/eventsource-ecosystem/eventsource"
+)
+
+type CheckpointMock struct {
+	s3iface.S3API
+
+	serializer eventsource.Serializer
+	event      eventsource.Event
+	keys       []string
+	lastMod    map[string]time.Time
+	seen       []string
+}
+
+func (m *CheckpointMock) ListObjectsV2WithContext(ctx aws.Context, input *s3.ListObjectsV2Input, opts ...request.Option) (*s3.ListObjectsV2Output, error) {
+	var contents []*s3.Object
+	for _, key := range m.keys {
+		contents = append(contents, &s3.Object{
+			Key:          aws.String(key),
+			LastModified: awsTimePtr(m.lastMod[key]),
+		})
+	}
+
+	return &s3.ListObjectsV2Output{Contents: contents}, nil
+}
+
+func awsTimePtr(t time.Time) *time.Time {
+	return &t
+}
+
+func (m *CheckpointMock) GetObjectWithContext(ctx aws.Context, input *s3.GetObjectInput, opts ...request.Option) (*s3.GetObjectOutput, error) {
+	m.seen = append(m.seen, *input.Key)
+
+	record, err := m.serializer.MarshalEvent(m.event)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString(base64.StdEncoding.EncodeToString(record.Data))
+	buf.WriteString("\n")
+
+	return &s3.GetObjectOutput{Body: ioutil.NopCloser(buf)}, nil
+}
+
+func TestReplayCheckpointSkipsCompletedKeys(t *testing.T) {
+	var (
+		ctx        = context.Background()
+		serializer = eventsource.NewJSONSerializer(Sample{})
+		now        = time.Now()
+		api        = &CheckpointMock{
+			serializer: serializer,
+			event:      Sample{Model: eventsource.Model{ID: "abc"}, Name: "blah"},
+			keys:       []string{"a", "b", "c"},
+			lastMod: map[string]time.Time{
+				"a": now, "b": now, "c": now,
+			},
+		}
+		checkpoint = &MemoryCheckpoint{}
+		fn         = func(ctx context.Context, event eventsource.Event) error { return nil }
+	)
+
+	if err := checkpoint.Save(ctx, "a"); err != nil {
+		t.Fatalf("got %v; want nil", err)
+	}
+
+	err := Replay(ctx, api, serializer, fn, "bucket", "prefix/", WithCheckpoint(checkpoint))
+	if err != nil {
+		t.Fatalf("got %v; want nil", err)
+	}
+
+	if got, want := api.seen, []string{"b", "c"}; !equalStrings(got, want) {
+		t.Fatalf("got %v; want %v", got, want)
+	}
+
+	key, err := checkpoint.Load(ctx)
+	if err != nil {
+		t.Fatalf("got %v; want nil", err)
+	}
+	if got, want := key, "c"; got != want {
+		t.Fatalf("got %v; want %v", got, want)
+	}
+}
+
+func TestReplayUntilFiltersLastModified(t *testing.T) {
+	var (
+		ctx        = context.Background()
+		serializer = eventsource.NewJSONSerializer(Sample{})
+		old        = time.Now().Add(-48 * time.Hour)
+		recent     = time.Now()
+		api        = &CheckpointMock{
+			serializer: serializer,
+			event:      Sample{Model: eventsource.Model{ID: "abc"}, Name: "blah"},
+			keys:       []string{"a", "b"},
+			lastMod: map[string]time.Time{
+				"a": old, "b": recent,
+			},
+		}
+		fn = func(ctx context.Context, event eventsource.Event) error { return nil }
+	)
+
+	err := Replay(ctx, api, serializer, fn, "bucket", "prefix/", WithUntil(recent.Add(time.Hour)))
+	if err != nil {
+		t.Fatalf("got %v; want nil", err)
+	}
+
+	if got, want := api.seen, []string{"a", "b"}; !equalStrings(got, want) {
+		t.Fatalf("got %v; want %v", got, want)
+	}
+
+	api.seen = nil
+	err = Replay(ctx, api, serializer, fn, "bucket", "prefix/", WithUntil(old.Add(time.Hour)))
+	if err != nil {
+		t.Fatalf("got %v; want nil", err)
+	}
+	if got, want := api.seen, []string{"a"}; !equalStrings(got, want) {
+		t.Fatalf("got %v; want %v", got, want)
+	}
+}
+
+func TestReplayRejectsInvertedWindow(t *testing.T) {
+	var (
+		ctx        = context.Background()
+		serializer = eventsource.NewJSONSerializer(Sample{})
+		api        = &CheckpointMock{serializer: serializer}
+		fn         = func(ctx context.Context, event eventsource.Event) error { return nil }
+		since      = time.Now()
+		until      = since.Add(-time.Hour)
+	)
+
+	if err := Replay(ctx, api, serializer, fn, "bucket", "prefix/", WithSince(since), WithUntil(until)); err == nil {
+		t.Fatal("got nil; want error for until before since")
+	}
+}
+
+func TestPartitionPrefixes(t *testing.T) {
+	since := time.Date(2020, 1, 1, 10, 30, 0, 0, time.UTC)
+	until := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	got := partitionPrefixes("events/", since, until)
+	want := []string{
+		"events/2020/01/01/10/",
+		"events/2020/01/01/11/",
+		"events/2020/01/01/12/",
+	}
+
+	if !equalStrings(got, want) {
+		t.Fatalf("got %v; want %v", got, want)
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,89 @@
+package s3firehose
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// RecordDecoder extracts individual serialized event records from a
+// Firehose-delivered S3 object body. Implementations invoke fn once per
+// record found, passing a human-readable position (line number or byte
+// offset) used to annotate poison record errors. Decoding stops as soon as
+// fn returns a non-nil error, and that error is returned to the caller.
+type RecordDecoder interface {
+	Decode(r io.Reader, bufSize int, fn func(pos string, data []byte) error) error
+}
+
+// Base64LineDecoder decodes the default Firehose-to-S3 destination format:
+// one base64-encoded record per newline-delimited line. It is the
+// RecordDecoder used by Replay and Watch when none is specified.
+type Base64LineDecoder struct{}
+
+func (Base64LineDecoder) Decode(r io.Reader, bufSize int, fn func(pos string, data []byte) error) error {
+	s := bufio.NewScanner(r)
+	if bufSize > 0 {
+		s.Buffer(make([]byte, 0, bufSize), bufSize)
+	}
+
+	for line := 1; s.Scan(); line++ {
+		pos := fmt.Sprintf("line %v", line)
+
+		data, err := base64.StdEncoding.DecodeString(s.Text())
+		if err != nil {
+			return fmt.Errorf("unable to base64 decode %v - %v", pos, err)
+		}
+
+		if err := fn(pos, data); err != nil {
+			return err
+		}
+	}
+
+	return s.Err()
+}
+
+// JSONLineDecoder decodes raw newline-delimited JSON records, with no
+// base64 envelope around each line.
+type JSONLineDecoder struct{}
+
+func (JSONLineDecoder) Decode(r io.Reader, bufSize int, fn func(pos string, data []byte) error) error {
+	s := bufio.NewScanner(r)
+	if bufSize > 0 {
+		s.Buffer(make([]byte, 0, bufSize), bufSize)
+	}
+
+	for line := 1; s.Scan(); line++ {
+		if err := fn(fmt.Sprintf("line %v", line), s.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	return s.Err()
+}
+
+// ConcatenatedJSONDecoder decodes Firehose's "no delimiter" record format,
+// where JSON records are written back-to-back with nothing separating them.
+// It relies on encoding/json's ability to decode a stream of consecutive
+// values. bufSize is unused; encoding/json manages its own buffering.
+type ConcatenatedJSONDecoder struct{}
+
+func (ConcatenatedJSONDecoder) Decode(r io.Reader, bufSize int, fn func(pos string, data []byte) error) error {
+	dec := json.NewDecoder(r)
+
+	for dec.More() {
+		offset := dec.InputOffset()
+
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return fmt.Errorf("unable to decode record at byte offset %v - %v", offset, err)
+		}
+
+		if err := fn(fmt.Sprintf("byte offset %v", offset), raw); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package s3firehose
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/eventsource-ecosystem/eventsource"
+)
+
+type GzipMock struct {
+	s3iface.S3API
+
+	serializer eventsource.Serializer
+	events     []eventsource.Event
+}
+
+func (m *GzipMock) GetObjectWithContext(ctx aws.Context, input *s3.GetObjectInput, opts ...request.Option) (*s3.GetObjectOutput, error) {
+	buf := bytes.NewBuffer(nil)
+	gz := gzip.NewWriter(buf)
+	for _, event := range m.events {
+		record, err := m.serializer.MarshalEvent(event)
+		if err != nil {
+			return nil, err
+		}
+
+		gz.Write([]byte(base64.StdEncoding.EncodeToString(record.Data)))
+		gz.Write([]byte("\n"))
+	}
+	gz.Close()
+
+	return &s3.GetObjectOutput{
+		Body:            ioutil.NopCloser(buf),
+		ContentEncoding: aws.String("gzip"),
+	}, nil
+}
+
+func TestHandleObjectGzip(t *testing.T) {
+	var (
+		ctx        = context.Background()
+		serializer = eventsource.NewJSONSerializer(Sample{})
+		event      = Sample{
+			Model: eventsource.Model{ID: "abc"},
+			Name:  "blah",
+		}
+		api = &GzipMock{
+			events:     []eventsource.Event{event},
+			serializer: serializer,
+		}
+		events []eventsource.Event
+		fn     = func(ctx context.Context, event eventsource.Event) error {
+			events = append(events, event)
+			return nil
+		}
+	)
+
+	err := handleObject(ctx, api, serializer, fn, "vavende-events-dev", "blah.gz", newOptions())
+	if err != nil {
+		t.Fatalf("got %v; want nil", err)
+	}
+
+	if got, want := len(events), 1; got != want {
+		t.Fatalf("got %v; want %v", got, want)
+	}
+}
+
+func TestConcatenatedJSONDecoder(t *testing.T) {
+	var (
+		r      = bytes.NewBufferString(`{"a":1}{"a":2}{"a":3}`)
+		got    []string
+		decode = ConcatenatedJSONDecoder{}
+	)
+
+	err := decode.Decode(r, 0, func(pos string, data []byte) error {
+		got = append(got, string(data))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("got %v; want nil", err)
+	}
+
+	if got, want := len(got), 3; got != want {
+		t.Fatalf("got %v; want %v", got, want)
+	}
+}
+
+func TestJSONLineDecoder(t *testing.T) {
+	var (
+		r      = bytes.NewBufferString("{\"a\":1}\n{\"a\":2}\n")
+		got    []string
+		decode = JSONLineDecoder{}
+	)
+
+	err := decode.Decode(r, 0, func(pos string, data []byte) error {
+		got = append(got, string(data))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("got %v; want nil", err)
+	}
+
+	if got, want := len(got), 2; got != want {
+		t.Fatalf("got %v; want %v", got, want)
+	}
+}
@@ -2,9 +2,12 @@ package s3firehose
 
 import (
 	"bufio"
+	"compress/gzip"
 	"context"
-	"encoding/base64"
 	"fmt"
+	"io"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/s3"
@@ -14,7 +17,104 @@ import (
 
 type HandlerFunc func(ctx context.Context, event eventsource.Event) error
 
-func handleObject(ctx context.Context, api s3iface.S3API, serializer eventsource.Serializer, fn HandlerFunc, bucket, key string) error {
+// Options configures how Replay and Watch read records out of S3 objects.
+type Options struct {
+	decoder    RecordDecoder
+	bufSize    int
+	since      time.Time
+	until      time.Time
+	checkpoint Checkpoint
+}
+
+// Option configures Options.
+type Option func(*Options)
+
+// WithRecordDecoder selects the RecordDecoder used to split an S3 object's
+// body into individual records. Defaults to Base64LineDecoder.
+func WithRecordDecoder(decoder RecordDecoder) Option {
+	return func(o *Options) {
+		o.decoder = decoder
+	}
+}
+
+// WithScannerBufferSize overrides the buffer size used by decoders backed by
+// bufio.Scanner. Large Firehose records can exceed bufio's default 64KB
+// token limit; use this to raise it.
+func WithScannerBufferSize(n int) Option {
+	return func(o *Options) {
+		o.bufSize = n
+	}
+}
+
+// WithSince restricts Replay to objects at or after t, generating the
+// minimal set of hour-partition prefixes (Firehose's YYYY/MM/DD/HH/ layout)
+// needed to cover the window instead of scanning the whole prefix. Only
+// those generated prefixes are listed, so objects under prefix that do not
+// follow that layout are not replayed.
+func WithSince(t time.Time) Option {
+	return func(o *Options) {
+		o.since = t
+	}
+}
+
+// WithUntil restricts Replay to objects at or before t. Combine with
+// WithSince to bound both ends of the partition scan; used alone it only
+// filters by LastModified, since the scan's start is unbounded.
+func WithUntil(t time.Time) Option {
+	return func(o *Options) {
+		o.until = t
+	}
+}
+
+// WithCheckpoint resumes Replay from the last object recorded by c,
+// advancing c after each object that completes without a handler error.
+// Objects are assumed to be processed in lexicographic key order, which
+// matches both ListObjectsV2's ordering and Firehose's time-partitioned key
+// layout.
+func WithCheckpoint(c Checkpoint) Option {
+	return func(o *Options) {
+		o.checkpoint = c
+	}
+}
+
+func newOptions(opts ...Option) Options {
+	options := Options{
+		decoder: Base64LineDecoder{},
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// maybeGunzip wraps r in a gzip.Reader when the object looks gzip
+// compressed, based on contentEncoding, a ".gz" key suffix, or the leading
+// 1f8b magic bytes.
+func maybeGunzip(r io.Reader, key string, contentEncoding *string) (io.Reader, error) {
+	br := bufio.NewReader(r)
+
+	gzipped := strings.HasSuffix(key, ".gz") ||
+		(contentEncoding != nil && strings.EqualFold(*contentEncoding, "gzip"))
+
+	if !gzipped {
+		if peek, err := br.Peek(2); err == nil && peek[0] == 0x1f && peek[1] == 0x8b {
+			gzipped = true
+		}
+	}
+
+	if !gzipped {
+		return br, nil
+	}
+
+	gzr, err := gzip.NewReader(br)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create gzip reader - %v", err)
+	}
+
+	return gzr, nil
+}
+
+func handleObject(ctx context.Context, api s3iface.S3API, serializer eventsource.Serializer, fn HandlerFunc, bucket, key string, options Options) error {
 	input := s3.GetObjectInput{
 		Bucket: aws.String(bucket),
 		Key:    aws.String(key),
@@ -26,19 +126,64 @@ func handleObject(ctx context.Context, api s3iface.S3API, serializer eventsource
 	}
 	defer output.Body.Close()
 
-	s := bufio.NewScanner(output.Body)
-	for line := 1; s.Scan(); line++ {
-		data, err := base64.StdEncoding.DecodeString(s.Text())
+	r, err := maybeGunzip(output.Body, key, output.ContentEncoding)
+	if err != nil {
+		return err
+	}
+	if gzr, ok := r.(*gzip.Reader); ok {
+		defer gzr.Close()
+	}
+
+	return options.decoder.Decode(r, options.bufSize, func(pos string, data []byte) error {
+		event, err := serializer.UnmarshalEvent(eventsource.Record{Data: data})
 		if err != nil {
-			return fmt.Errorf("unable to base64 decode line %v - %v", line, err)
+			return fmt.Errorf("unable to unmarshal event at %v - %v", pos, err)
 		}
 
-		event, err := serializer.UnmarshalEvent(eventsource.Record{Data: data})
+		return fn(ctx, event)
+	})
+}
+
+// partitionPrefixes expands prefix into the hour-partition prefixes
+// (prefix + "YYYY/MM/DD/HH/") that need to be listed to cover [since, until].
+// If since is zero, Replay has no lower bound to partition from and the
+// unmodified prefix is scanned in full.
+func partitionPrefixes(prefix string, since, until time.Time) []string {
+	if since.IsZero() {
+		return []string{prefix}
+	}
+
+	if until.IsZero() {
+		until = time.Now()
+	}
+	since, until = since.UTC(), until.UTC()
+
+	var prefixes []string
+	for t := since.Truncate(time.Hour); !t.After(until); t = t.Add(time.Hour) {
+		prefixes = append(prefixes, fmt.Sprintf("%v%04d/%02d/%02d/%02d/", prefix, t.Year(), t.Month(), t.Day(), t.Hour()))
+	}
+
+	return prefixes
+}
+
+func Replay(ctx context.Context, api s3iface.S3API, serializer eventsource.Serializer, fn HandlerFunc, bucket, prefix string, opts ...Option) error {
+	options := newOptions(opts...)
+
+	if !options.since.IsZero() && !options.until.IsZero() && options.until.Before(options.since) {
+		return fmt.Errorf("invalid replay window, until %v is before since %v", options.until, options.since)
+	}
+
+	var resumeKey string
+	if options.checkpoint != nil {
+		key, err := options.checkpoint.Load(ctx)
 		if err != nil {
-			return fmt.Errorf("unable to unmarshal event - %v", err)
+			return fmt.Errorf("unable to load checkpoint - %v", err)
 		}
+		resumeKey = key
+	}
 
-		if err := fn(ctx, event); err != nil {
+	for _, p := range partitionPrefixes(prefix, options.since, options.until) {
+		if err := replayPrefix(ctx, api, serializer, fn, bucket, p, resumeKey, options); err != nil {
 			return err
 		}
 	}
@@ -46,7 +191,7 @@ func handleObject(ctx context.Context, api s3iface.S3API, serializer eventsource
 	return nil
 }
 
-func Replay(ctx context.Context, api s3iface.S3API, serializer eventsource.Serializer, fn HandlerFunc, bucket, prefix string) error {
+func replayPrefix(ctx context.Context, api s3iface.S3API, serializer eventsource.Serializer, fn HandlerFunc, bucket, prefix, resumeKey string, options Options) error {
 	var token *string
 
 	for {
@@ -62,9 +207,25 @@ func Replay(ctx context.Context, api s3iface.S3API, serializer eventsource.Seria
 		}
 
 		for _, item := range output.Contents {
-			if err := handleObject(ctx, api, serializer, fn, bucket, *item.Key); err != nil {
+			if resumeKey != "" && *item.Key <= resumeKey {
+				continue
+			}
+			if !options.since.IsZero() && item.LastModified != nil && item.LastModified.Before(options.since) {
+				continue
+			}
+			if !options.until.IsZero() && item.LastModified != nil && item.LastModified.After(options.until) {
+				continue
+			}
+
+			if err := handleObject(ctx, api, serializer, fn, bucket, *item.Key, options); err != nil {
 				return fmt.Errorf("unable to process s3 object, s3://%v/%v - %v", bucket, *item.Key, err)
 			}
+
+			if options.checkpoint != nil {
+				if err := options.checkpoint.Save(ctx, *item.Key); err != nil {
+					return fmt.Errorf("unable to save checkpoint at s3://%v/%v - %v", bucket, *item.Key, err)
+				}
+			}
 		}
 
 		token = output.NextContinuationToken
@@ -0,0 +1,236 @@
+package s3firehose
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/eventsource-ecosystem/eventsource"
+	"golang.org/x/sync/errgroup"
+)
+
+// Subscription represents an active Watch subscription. Call Close to stop
+// consuming notifications and wait for the underlying goroutines to exit.
+type Subscription struct {
+	cancel context.CancelFunc
+	group  *errgroup.Group
+}
+
+func (s *Subscription) Close() error {
+	s.cancel()
+	return s.group.Wait()
+}
+
+// s3EventNotification is the subset of the S3 event notification message
+// delivered to SQS that Watch needs in order to locate the object(s) to
+// replay. Notifications sent when the subscription is first created (e.g.
+// "s3:TestEvent") have no Records and are acked without further action.
+type s3EventNotification struct {
+	Records []struct {
+		S3 struct {
+			Bucket struct {
+				Name string `json:"name"`
+			} `json:"bucket"`
+			Object struct {
+				Key string `json:"key"`
+			} `json:"object"`
+		} `json:"s3"`
+	} `json:"Records"`
+}
+
+// Watch subscribes to queueName, an SQS queue fed by S3 event notifications,
+// and streams the objects referenced by each notification through fn via the
+// same handleObject pipeline used by Replay. Unlike Replay, which enumerates
+// a prefix with ListObjectsV2, Watch reacts to objects as Firehose delivers
+// them, making it suitable for near-real-time replay. Only WithRecordDecoder
+// and WithScannerBufferSize apply to Watch; WithSince, WithUntil, and
+// WithCheckpoint are Replay-only and are ignored here since there is no
+// listing or resumable ordering to apply them to.
+func Watch(ctx context.Context, s3Client s3iface.S3API, sqsClient sqsiface.SQSAPI, serializer eventsource.Serializer, fn HandlerFunc, queueName string, opts ...Option) (*Subscription, error) {
+	queueUrl, err := findQueueURL(sqsClient, queueName)
+	if err != nil {
+		return nil, err
+	}
+
+	options := newOptions(opts...)
+
+	var (
+		received       = make(chan *sqs.Message, 10)
+		completed      = make(chan *sqs.Message, 10)
+		parent, cancel = context.WithCancel(context.Background())
+		group, gctx    = errgroup.WithContext(parent)
+		sub            = &Subscription{cancel: cancel, group: group}
+	)
+
+	group.Go(func() error {
+		return receiveLoop(gctx, sqsClient, queueUrl, received)
+	})
+	group.Go(func() error {
+		return handleLoop(gctx, s3Client, serializer, fn, received, completed, options)
+	})
+	group.Go(func() error {
+		return deleteLoop(gctx, sqsClient, queueUrl, 15*time.Second, completed)
+	})
+
+	return sub, nil
+}
+
+func findQueueURL(api sqsiface.SQSAPI, queueName string) (*string, error) {
+	output, err := api.ListQueues(&sqs.ListQueuesInput{
+		QueueNamePrefix: aws.String(queueName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sqs.ListQueues failed - %v", err)
+	}
+
+	for _, item := range output.QueueUrls {
+		if strings.HasSuffix(*item, "/"+queueName) {
+			return item, nil
+		}
+	}
+
+	return nil, fmt.Errorf("queue not found, %v", queueName)
+}
+
+func receiveLoop(ctx context.Context, api sqsiface.SQSAPI, queueUrl *string, received chan *sqs.Message) error {
+	for {
+		output, err := api.ReceiveMessageWithContext(ctx, &sqs.ReceiveMessageInput{
+			MaxNumberOfMessages: aws.Int64(10),
+			QueueUrl:            queueUrl,
+			VisibilityTimeout:   aws.Int64(240),
+			WaitTimeSeconds:     aws.Int64(20),
+		})
+		if err != nil {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(15 * time.Second):
+				continue
+			}
+		}
+
+		for _, m := range output.Messages {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case received <- m:
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+	}
+}
+
+func handleLoop(ctx context.Context, api s3iface.S3API, serializer eventsource.Serializer, fn HandlerFunc, received, completed chan *sqs.Message, options Options) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case m := <-received:
+			if err := handleS3Event(ctx, api, serializer, fn, m, options); err != nil {
+				return err
+			}
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case completed <- m:
+			}
+		}
+	}
+}
+
+func handleS3Event(ctx context.Context, api s3iface.S3API, serializer eventsource.Serializer, fn HandlerFunc, m *sqs.Message, options Options) error {
+	if m == nil || m.Body == nil {
+		return nil
+	}
+
+	var notification s3EventNotification
+	if err := json.Unmarshal([]byte(*m.Body), &notification); err != nil {
+		return fmt.Errorf("unable to unmarshal s3 event notification - %v", err)
+	}
+
+	if len(notification.Records) == 0 {
+		// S3 sends a test notification with no Records when the
+		// subscription is first created; ack it and move on.
+		return nil
+	}
+
+	for _, record := range notification.Records {
+		bucket := record.S3.Bucket.Name
+		key := record.S3.Object.Key
+		if unescaped, err := url.QueryUnescape(key); err == nil {
+			key = unescaped
+		}
+
+		if err := handleObject(ctx, api, serializer, fn, bucket, key, options); err != nil {
+			return fmt.Errorf("unable to process s3 object, s3://%v/%v - %v", bucket, key, err)
+		}
+	}
+
+	return nil
+}
+
+func deleteLoop(ctx context.Context, api sqsiface.SQSAPI, queueUrl *string, interval time.Duration, completed chan *sqs.Message) error {
+	input := &sqs.DeleteMessageBatchInput{
+		QueueUrl: queueUrl,
+	}
+
+	deleteMessages := func() {
+		if len(input.Entries) == 0 {
+			return
+		}
+
+		for attempt := 1; attempt <= 3; attempt++ {
+			if _, err := api.DeleteMessageBatchWithContext(ctx, input); err != nil {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(15 * time.Second):
+					continue
+				}
+			}
+
+			break
+		}
+
+		input.Entries = nil // reset Entries
+	}
+	defer deleteMessages()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case <-ticker.C:
+			deleteMessages()
+
+		case m := <-completed:
+			input.Entries = append(input.Entries, &sqs.DeleteMessageBatchRequestEntry{
+				Id:            aws.String(strconv.Itoa(len(input.Entries))),
+				ReceiptHandle: m.ReceiptHandle,
+			})
+		}
+
+		if len(input.Entries) == 10 {
+			deleteMessages()
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package s3firehose
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/eventsource-ecosystem/eventsource"
+)
+
+type WatchMockS3 struct {
+	s3iface.S3API
+
+	serializer eventsource.Serializer
+	events     []eventsource.Event
+}
+
+func (m *WatchMockS3) GetObjectWithContext(ctx aws.Context, input *s3.GetObjectInput, opts ...request.Option) (*s3.GetObjectOutput, error) {
+	buf := bytes.NewBuffer(nil)
+	for _, event := range m.events {
+		record, err := m.serializer.MarshalEvent(event)
+		if err != nil {
+			return nil, err
+		}
+
+		buf.WriteString(base64.StdEncoding.EncodeToString(record.Data))
+		buf.WriteString("\n")
+	}
+
+	return &s3.GetObjectOutput{
+		Body: ioutil.NopCloser(buf),
+	}, nil
+}
+
+type WatchMockSQS struct {
+	sqsiface.SQSAPI
+
+	sent bool
+}
+
+func (m *WatchMockSQS) ListQueues(input *sqs.ListQueuesInput) (*sqs.ListQueuesOutput, error) {
+	return &sqs.ListQueuesOutput{
+		QueueUrls: []*string{
+			aws.String("/" + *input.QueueNamePrefix),
+		},
+	}, nil
+}
+
+func (m *WatchMockSQS) ReceiveMessageWithContext(ctx aws.Context, input *sqs.ReceiveMessageInput, opts ...request.Option) (*sqs.ReceiveMessageOutput, error) {
+	if m.sent {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	m.sent = true
+
+	return &sqs.ReceiveMessageOutput{
+		Messages: []*sqs.Message{
+			{
+				Body: aws.String(`{"Records":[{"s3":{"bucket":{"name":"vavende-events-dev"},"object":{"key":"blah"}}}]}`),
+			},
+			{
+				Body: aws.String(`{"Service":"Amazon S3","Event":"s3:TestEvent"}`),
+			},
+		},
+	}, nil
+}
+
+func (m *WatchMockSQS) DeleteMessageBatchWithContext(aws.Context, *sqs.DeleteMessageBatchInput, ...request.Option) (*sqs.DeleteMessageBatchOutput, error) {
+	return nil, nil
+}
+
+func TestWatch(t *testing.T) {
+	var (
+		ctx        = context.Background()
+		serializer = eventsource.NewJSONSerializer(Sample{})
+		event      = Sample{
+			Model: eventsource.Model{ID: "abc"},
+			Name:  "blah",
+		}
+		s3Client = &WatchMockS3{
+			events:     []eventsource.Event{event},
+			serializer: serializer,
+		}
+		sqsClient = &WatchMockSQS{}
+		done      = make(chan struct{})
+		events    []eventsource.Event
+		fn        = func(ctx context.Context, event eventsource.Event) error {
+			events = append(events, event)
+			close(done)
+			return nil
+		}
+	)
+
+	sub, err := Watch(ctx, s3Client, sqsClient, serializer, fn, "blah")
+	if err != nil {
+		t.Fatalf("got %v; want nil", err)
+	}
+	defer sub.Close()
+
+	<-done
+
+	if got, want := len(events), 1; got != want {
+		t.Fatalf("got %v; want %v", got, want)
+	}
+}